@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	mpris2 "git.hrfee.pw/hrfee/waybar-mpris/mpris2client"
+)
+
+// respond writes a socket response for the player-control commands (seek,
+// volume, shuffle, loop, stop, play, pause), so waybar scroll/click bindings
+// can surface failures instead of silently doing nothing.
+func respond(con net.Conn, err error) {
+	if err != nil {
+		fmt.Fprintf(con, "error: %v", err)
+		return
+	}
+	fmt.Fprint(con, "ok")
+}
+
+// handleSeek implements "seek +5", "seek -5" (relative, in seconds) and
+// "seek 30%" (absolute, as a percentage of the track's length).
+func handleSeek(p *mpris2.Player, arg string) error {
+	if arg == "" {
+		return fmt.Errorf("seek needs an argument, e.g. +5, -5 or 30%%")
+	}
+	if strings.HasSuffix(arg, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid seek percentage %q: %w", arg, err)
+		}
+		_, err = p.SeekToPercent(pct)
+		return err
+	}
+	seconds, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seek offset %q: %w", arg, err)
+	}
+	_, err = p.Seek(int64(seconds*1e6), io.SeekCurrent)
+	return err
+}
+
+// handleVolume implements "volume +5", "volume -5" (relative, percentage
+// points) and "volume =80" (absolute percentage).
+func handleVolume(p *mpris2.Player, arg string) error {
+	if len(arg) < 2 {
+		return fmt.Errorf("volume needs an argument, e.g. +5, -5 or =80")
+	}
+	delta, err := strconv.ParseFloat(arg[1:], 64)
+	if err != nil {
+		return fmt.Errorf("invalid volume argument %q: %w", arg, err)
+	}
+	switch arg[0] {
+	case '=':
+		return p.SetVolume(delta / 100)
+	case '+':
+		current, err := p.GetVolume()
+		if err != nil {
+			return err
+		}
+		return p.SetVolume(current + delta/100)
+	case '-':
+		current, err := p.GetVolume()
+		if err != nil {
+			return err
+		}
+		return p.SetVolume(current - delta/100)
+	default:
+		return fmt.Errorf("volume argument must start with +, - or =, got %q", arg)
+	}
+}
+
+// handleShuffle implements "shuffle toggle", "shuffle on" and "shuffle off".
+func handleShuffle(p *mpris2.Player, arg string) error {
+	switch arg {
+	case "on":
+		return p.SetShuffle(true)
+	case "off":
+		return p.SetShuffle(false)
+	case "toggle":
+		current, err := p.GetShuffle()
+		if err != nil {
+			return err
+		}
+		return p.SetShuffle(!current)
+	default:
+		return fmt.Errorf("shuffle argument must be toggle, on or off, got %q", arg)
+	}
+}
+
+// handleLoop implements "loop none", "loop track" and "loop playlist".
+func handleLoop(p *mpris2.Player, arg string) error {
+	switch arg {
+	case "none":
+		return p.SetLoopStatus("None")
+	case "track":
+		return p.SetLoopStatus("Track")
+	case "playlist":
+		return p.SetLoopStatus("Playlist")
+	default:
+		return fmt.Errorf("loop argument must be none, track or playlist, got %q", arg)
+	}
+}
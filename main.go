@@ -10,9 +10,9 @@ import (
 	"strings"
 	"time"
 
+	mpris2 "git.hrfee.pw/hrfee/waybar-mpris/mpris2client"
 	"github.com/fsnotify/fsnotify"
 	"github.com/godbus/dbus/v5"
-	mpris2 "github.com/hrfee/mpris2client"
 	flag "github.com/spf13/pflag"
 )
 
@@ -26,102 +26,34 @@ const (
 
 // Mostly default values for flag options.
 var (
-	PLAY      = "▶"
-	PAUSE     = ""
-	SEP       = " - "
-	ORDER     = "SYMBOL:ARTIST:ALBUM:TITLE:POSITION"
-	AUTOFOCUS = false
+	TEMPLATE_FILE = ""
+	TEMPLATES     = templateConfig{Default: defaultFieldTemplates(), Players: map[string]fieldTemplates{}}
+	AUTOFOCUS     = false
 	// Available commands that can be sent to running instances.
-	COMMANDS              = []string{"player-next", "player-prev", "next", "prev", "toggle", "list"}
+	COMMANDS = []string{
+		"player-next", "player-prev", "next", "prev", "toggle", "stop", "play", "pause",
+		"seek +5/seek -5/seek 30%", "volume +5/volume -5/volume =80", "shuffle toggle/on/off",
+		"loop none/track/playlist", "list",
+	}
 	SHOW_POS              = false
 	INTERPOLATE           = false
+	NO_ART                = false
+	ART_SIZE              = 64
+	INCLUDE               = ""
+	EXCLUDE               = ""
+	PRIORITY              = ""
 	REPLACE               = false
 	isSharing             = false
 	WRITER      io.Writer = os.Stdout
 )
 
-// JSON returns json for waybar to consume.
-func playerJSON(p *mpris2.Player) string {
-	symbol := PLAY
-	out := "{\"class\": \""
-	if p.Playing {
-		symbol = PAUSE
-		out += "playing"
-	} else {
-		out += "paused"
-	}
-	var pos string
-	if SHOW_POS {
-		pos = p.StringPosition()
-		if pos != "" {
-			pos = "(" + pos + ")"
-		}
-	}
-	var items []string
-	order := strings.Split(ORDER, ":")
-	for _, v := range order {
-		switch v {
-		case "SYMBOL":
-			items = append(items, symbol)
-		case "ARTIST":
-			if p.Artist != "" {
-				items = append(items, p.Artist)
-			}
-		case "ALBUM":
-			if p.Album != "" {
-				items = append(items, p.Album)
-			}
-		case "TITLE":
-			if p.Title != "" {
-				items = append(items, p.Title)
-			}
-		case "POSITION":
-			if pos != "" && SHOW_POS {
-				items = append(items, pos)
-			}
-		}
-	}
-	if len(items) == 0 {
-		return "{}"
-	}
-	text := ""
-	for i, v := range items {
-		right := ""
-		if (v == symbol || v == pos) && i != len(items)-1 {
-			right = " "
-		} else if i != len(items)-1 && items[i+1] != symbol && items[i+1] != pos {
-			right = SEP
-		} else {
-			right = " "
-		}
-		text += v + right
-	}
-	out += "\",\"text\":\"" + text + "\","
-	out += "\"tooltip\":\"" + fmt.Sprintf(
-		"%s\\nby %s\\n",
-		strings.ReplaceAll(p.Title, "&", "&amp;"),
-		strings.ReplaceAll(p.Artist, "&", "&amp;"),
-	)
-	if p.Album != "" {
-		out += "from " + strings.ReplaceAll(p.Album, "&", "&amp;") + "\\n"
-	}
-	out += "(" + p.Name + ")\"}"
-	return out
-	// return fmt.Sprintf("{\"class\":\"%s\",\"text\":\"%s\",\"tooltip\":\"%s\"}", data["class"], data["text"], data["tooltip"])
-	// out, err := json.Marshal(data)
-	// if err != nil {
-	// 	return "{}"
-	// }
-	// return string(out)
-}
-
 type players struct {
 	mpris2 *mpris2.Mpris2
 }
 
 func (pl *players) JSON() string {
 	if len(pl.mpris2.List) != 0 {
-		return playerJSON(pl.mpris2.List[pl.mpris2.Current])
+		return renderJSON(pl.mpris2.List[pl.mpris2.Current])
 	}
 	return "{}"
 }
@@ -132,6 +64,8 @@ func (pl *players) Prev() { pl.mpris2.List[pl.mpris2.Current].Previous() }
 
 func (pl *players) Toggle() { pl.mpris2.List[pl.mpris2.Current].Toggle() }
 
+func (pl *players) current() *mpris2.Player { return pl.mpris2.List[pl.mpris2.Current] }
+
 func execCommand(cmd string) {
 	conn, err := net.Dial("unix", SOCK)
 	if err != nil {
@@ -142,7 +76,12 @@ func execCommand(cmd string) {
 		log.Fatalln("Couldn't send command")
 	}
 	fmt.Println("Sent.")
-	if cmd == "list" {
+	verb := strings.Fields(cmd)
+	respondingCommands := map[string]bool{
+		"list": true, "stop": true, "play": true, "pause": true,
+		"seek": true, "volume": true, "shuffle": true, "loop": true,
+	}
+	if len(verb) > 0 && respondingCommands[verb[0]] {
 		buf := make([]byte, 512)
 		nr, err := conn.Read(buf)
 		if err != nil {
@@ -250,7 +189,15 @@ func listenForCommands(players *players) {
 			continue
 		}
 		command := string(buf[0:nr])
-		switch command {
+		fields := strings.Fields(command)
+		var verb, arg string
+		if len(fields) > 0 {
+			verb = fields[0]
+		}
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+		switch verb {
 		case "player-next":
 			length := len(players.mpris2.List)
 			if length != 1 {
@@ -277,6 +224,28 @@ func listenForCommands(players *players) {
 			players.Prev()
 		case "toggle":
 			players.Toggle()
+		case "stop", "play", "pause", "seek", "volume", "shuffle", "loop":
+			if len(players.mpris2.List) == 0 {
+				respond(con, fmt.Errorf("no players"))
+				break
+			}
+			p := players.current()
+			switch verb {
+			case "stop":
+				respond(con, p.Stop())
+			case "play":
+				respond(con, p.Play())
+			case "pause":
+				respond(con, p.Pause())
+			case "seek":
+				respond(con, handleSeek(p, arg))
+			case "volume":
+				respond(con, handleVolume(p, arg))
+			case "shuffle":
+				respond(con, handleShuffle(p, arg))
+			case "loop":
+				respond(con, handleLoop(p, arg))
+			}
 		case "list":
 			con.Write([]byte(players.mpris2.String()))
 		case "share":
@@ -316,13 +285,15 @@ func main() {
 	}
 	mw := io.MultiWriter(logfile, os.Stdout)
 	log.SetOutput(mw)
-	flag.StringVar(&PLAY, "play", PLAY, "Play symbol/text to use.")
-	flag.StringVar(&PAUSE, "pause", PAUSE, "Pause symbol/text to use.")
-	flag.StringVar(&SEP, "separator", SEP, "Separator string to use between artist, album, and title.")
-	flag.StringVar(&ORDER, "order", ORDER, "Element order.")
+	flag.StringVar(&TEMPLATE_FILE, "template-file", TEMPLATE_FILE, "Path to a file defining [default]/[players.<name>] class/text/tooltip/alt templates, overriding the built-in ones.")
 	flag.BoolVar(&AUTOFOCUS, "autofocus", AUTOFOCUS, "Auto switch to currently playing music players.")
 	flag.BoolVar(&SHOW_POS, "position", SHOW_POS, "Show current position between brackets, e.g (04:50/05:00)")
 	flag.BoolVar(&INTERPOLATE, "interpolate", INTERPOLATE, "Interpolate track position (helpful for players that don't update regularly, e.g mpDris2)")
+	flag.BoolVar(&NO_ART, "no-art", NO_ART, "Don't download/cache cover art, and leave ArtPath empty in templates.")
+	flag.IntVar(&ART_SIZE, "art-size", ART_SIZE, "Size (in pixels) used for the <img> tag in the default tooltip template's cover art.")
+	flag.StringVar(&INCLUDE, "include", INCLUDE, "Comma-separated glob patterns; only players matching FullName or Name are tracked. Exclude is checked first.")
+	flag.StringVar(&EXCLUDE, "exclude", EXCLUDE, "Comma-separated glob patterns; players matching FullName or Name are never tracked.")
+	flag.StringVar(&PRIORITY, "priority", PRIORITY, "Comma-separated player names in preferred order, e.g. \"spotify,mpv,firefox\", used to break ties between equally-playing players.")
 	flag.BoolVar(&REPLACE, "replace", REPLACE, "replace existing waybar-mpris if found. When false, new instance will clone the original instances output.")
 	var command string
 	flag.StringVar(&command, "send", "", "send command to already runnning waybar-mpris instance. (options: "+strings.Join(COMMANDS, "/")+")")
@@ -330,6 +301,23 @@ func main() {
 	flag.Parse()
 	os.Stderr = logfile
 
+	if TEMPLATE_FILE != "" {
+		cfg, err := loadTemplateConfig(TEMPLATE_FILE)
+		if err != nil {
+			log.Fatalf("Couldn't load template file %q: %v", TEMPLATE_FILE, err)
+		}
+		TEMPLATES = *cfg
+	}
+	if INCLUDE != "" {
+		mpris2.Include = strings.Split(INCLUDE, ",")
+	}
+	if EXCLUDE != "" {
+		mpris2.Exclude = strings.Split(EXCLUDE, ",")
+	}
+	if PRIORITY != "" {
+		mpris2.Priority = strings.Split(PRIORITY, ",")
+	}
+
 	if command != "" {
 		execCommand(command)
 	}
@@ -369,7 +357,7 @@ func main() {
 		log.Fatalln("Error connecting to DBus:", err)
 	}
 	players := &players{
-		mpris2: mpris2.NewMpris2(conn, INTERPOLATE, POLL, AUTOFOCUS),
+		mpris2: mpris2.NewMpris2(conn, INTERPOLATE, POLL, AUTOFOCUS, NO_ART),
 	}
 	players.mpris2.Reload()
 	players.mpris2.Sort()
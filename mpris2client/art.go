@@ -0,0 +1,143 @@
+package mpris2client
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cache used for cover art fetched via Player.Refresh. Entries older than
+// artCacheTTL, or beyond artCacheMaxFiles (oldest first), are evicted after
+// every successful download.
+const (
+	artCacheTTL      = 30 * 24 * time.Hour
+	artCacheMaxFiles = 200
+)
+
+func artCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "waybar-mpris", "art"), nil
+}
+
+// artCachePath returns where a given artUrl would be cached, without
+// touching the filesystem.
+func artCachePath(dir, artURL string) string {
+	ext := path.Ext(artURL)
+	if len(ext) == 0 || len(ext) > 5 {
+		ext = ".img"
+	}
+	sum := sha1.Sum([]byte(artURL))
+	return filepath.Join(dir, fmt.Sprintf("%x%s", sum, ext))
+}
+
+// fetchArt downloads or copies artURL into the art cache and stores the
+// result via p.setArtPath, running entirely in the background so a slow
+// fetch never blocks the DBus signal loop (Refresh starts this as a
+// goroutine).
+func (p *Player) fetchArt(artURL string) {
+	dir, err := artCacheDir()
+	if err != nil {
+		return
+	}
+	dest := artCachePath(dir, artURL)
+
+	if info, err := os.Stat(dest); err == nil && info.Size() > 0 {
+		now := time.Now()
+		os.Chtimes(dest, now, now)
+		p.setArtPath(dest)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	var src io.ReadCloser
+	switch {
+	case strings.HasPrefix(artURL, "http://"), strings.HasPrefix(artURL, "https://"):
+		resp, err := http.Get(artURL)
+		if err != nil {
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return
+		}
+		src = resp.Body
+	case strings.HasPrefix(artURL, "file://"):
+		f, err := os.Open(strings.TrimPrefix(artURL, "file://"))
+		if err != nil {
+			return
+		}
+		src = f
+	default:
+		return
+	}
+	defer src.Close()
+
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return
+	}
+	out.Close()
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	p.setArtPath(dest)
+	evictArtCache(dir)
+}
+
+// evictArtCache removes cached art older than artCacheTTL, then trims the
+// remainder down to artCacheMaxFiles, oldest (by mtime) first.
+func evictArtCache(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	now := time.Now()
+	files := make([]cacheFile, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		if now.Sub(info.ModTime()) > artCacheTTL {
+			os.Remove(p)
+			continue
+		}
+		files = append(files, cacheFile{path: p, modTime: info.ModTime()})
+	}
+	if len(files) <= artCacheMaxFiles {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files[:len(files)-artCacheMaxFiles] {
+		os.Remove(f.path)
+	}
+}
@@ -0,0 +1,120 @@
+package mpris2client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// setProperty sets a property on the Player interface via
+// org.freedesktop.DBus.Properties.Set.
+func (p *Player) setProperty(name string, value interface{}) error {
+	return p.Player.Call("org.freedesktop.DBus.Properties.Set", 0, INTERFACE+".Player", name, dbus.MakeVariant(value)).Err
+}
+
+// Stop requests playback stop.
+func (p *Player) Stop() error { return p.Player.Call(INTERFACE+".Player.Stop", 0).Err }
+
+// Play requests playback.
+func (p *Player) Play() error { return p.Player.Call(INTERFACE+".Player.Play", 0).Err }
+
+// Pause requests a pause.
+func (p *Player) Pause() error { return p.Player.Call(INTERFACE+".Player.Pause", 0).Err }
+
+// Seek moves the current track's position and returns the resulting
+// position, microsecond-based like io.Seeker. whence=io.SeekCurrent seeks
+// by offsetUs relative to the current position (a negative offset
+// rewinds); whence=io.SeekStart seeks to the absolute offsetUs position,
+// using the track ID from the current metadata as SetPosition requires.
+func (p *Player) Seek(offsetUs int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		if err := p.Player.Call(INTERFACE+".Player.Seek", 0, offsetUs).Err; err != nil {
+			return 0, err
+		}
+	case io.SeekStart:
+		trackID, ok := p.metadata["mpris:trackid"].Value().(dbus.ObjectPath)
+		if !ok {
+			return 0, fmt.Errorf("no current track id")
+		}
+		if err := p.Player.Call(INTERFACE+".Player.SetPosition", 0, trackID, offsetUs).Err; err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported whence %d", whence)
+	}
+	pos, _ := p.getPosition()
+	return pos, nil
+}
+
+// SeekToPercent seeks to pct (0-100) percent of the current track's
+// length and returns the resulting position.
+func (p *Player) SeekToPercent(pct float64) (int64, error) {
+	if pct < 0 {
+		pct = 0
+	} else if pct > 100 {
+		pct = 100
+	}
+	position := int64(float64(p.Length) * 1e6 * pct / 100)
+	return p.Seek(position, io.SeekStart)
+}
+
+// GetVolume returns the current volume, usually in the 0.0-1.0 range
+// (though MPRIS allows players to go over 1.0).
+func (p *Player) GetVolume() (float64, error) {
+	v, err := p.Player.GetProperty(INTERFACE + ".Player.Volume")
+	if err != nil {
+		return 0, err
+	}
+	vol, ok := v.Value().(float64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected Volume type %T", v.Value())
+	}
+	return vol, nil
+}
+
+// SetVolume sets the volume. Negative values are clamped to 0.
+func (p *Player) SetVolume(vol float64) error {
+	if vol < 0 {
+		vol = 0
+	}
+	return p.setProperty("Volume", vol)
+}
+
+// GetShuffle returns whether shuffle is enabled.
+func (p *Player) GetShuffle() (bool, error) {
+	v, err := p.Player.GetProperty(INTERFACE + ".Player.Shuffle")
+	if err != nil {
+		return false, err
+	}
+	shuffle, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected Shuffle type %T", v.Value())
+	}
+	return shuffle, nil
+}
+
+// SetShuffle enables or disables shuffle.
+func (p *Player) SetShuffle(shuffle bool) error {
+	return p.setProperty("Shuffle", shuffle)
+}
+
+// GetLoopStatus returns the current loop status: "None", "Track" or
+// "Playlist".
+func (p *Player) GetLoopStatus() (string, error) {
+	v, err := p.Player.GetProperty(INTERFACE + ".Player.LoopStatus")
+	if err != nil {
+		return "", err
+	}
+	status, ok := v.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected LoopStatus type %T", v.Value())
+	}
+	return status, nil
+}
+
+// SetLoopStatus sets the loop status: "None", "Track" or "Playlist".
+func (p *Player) SetLoopStatus(status string) error {
+	return p.setProperty("LoopStatus", status)
+}
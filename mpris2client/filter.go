@@ -0,0 +1,60 @@
+package mpris2client
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Include, Exclude and Priority configure which players Mpris2 tracks and
+// how PlayerArray.Less orders them. They're set by the frontend (e.g. from
+// --include/--exclude/--priority flags) before players are discovered.
+var (
+	// Include, if non-empty, only allows players whose FullName or friendly
+	// Name matches at least one of these glob patterns (filepath.Match
+	// syntax). Exclude is checked first, so it always wins over Include.
+	Include []string
+	// Exclude hides players whose FullName or friendly Name matches any of
+	// these glob patterns, regardless of Include.
+	Exclude []string
+	// Priority orders players by friendly Name, most preferred first, for
+	// use when multiple players are in the same playing state. Names not
+	// listed sort after all listed ones, alphabetically by Name.
+	Priority []string
+)
+
+func globMatchesEither(pattern, fullName, name string) bool {
+	if ok, err := filepath.Match(pattern, fullName); err == nil && ok {
+		return true
+	}
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
+
+// allowed reports whether a player should be tracked, per Include/Exclude.
+func allowed(fullName, name string) bool {
+	for _, pattern := range Exclude {
+		if globMatchesEither(pattern, fullName, name) {
+			return false
+		}
+	}
+	if len(Include) == 0 {
+		return true
+	}
+	for _, pattern := range Include {
+		if globMatchesEither(pattern, fullName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityIndex returns name's position in Priority (case-insensitive), or
+// len(Priority) if it isn't listed.
+func priorityIndex(name string) int {
+	for i, p := range Priority {
+		if strings.EqualFold(p, name) {
+			return i
+		}
+	}
+	return len(Priority)
+}
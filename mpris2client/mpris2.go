@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -36,6 +37,8 @@ var knownBrowsers = map[string]string{
 type Player struct {
 	Player                                            dbus.BusObject
 	FullName, Name, Title, Artist, AlbumArtist, Album string
+	Genre, ArtURL                                     string
+	TrackNumber, Length                               int // -1 when track number unavailable
 	Position                                          int64
 	pid                                               uint32
 	Playing, Stopped                                  bool
@@ -43,10 +46,35 @@ type Player struct {
 	conn                                              *dbus.Conn
 	poll                                              int
 	interpolate                                       bool
+	noArt                                             bool
+	lastArtURL                                        string
+	// artPathMu guards artPath, which fetchArt populates from a
+	// goroutine spawned by Refresh, independently of whatever else is
+	// reading the Player concurrently (e.g. a JSON render in flight).
+	artPathMu sync.Mutex
+	artPath   string
 }
 
-// NewPlayer returns a new player object.
-func NewPlayer(conn *dbus.Conn, name string, interpolate bool, poll int) (p *Player) {
+// ArtPath returns the local, cached copy of ArtURL (see Refresh), usable
+// directly in a waybar tooltip's Pango <img> markup. Empty until the
+// background fetch started by Refresh completes.
+func (p *Player) ArtPath() string {
+	p.artPathMu.Lock()
+	defer p.artPathMu.Unlock()
+	return p.artPath
+}
+
+// setArtPath is called by Refresh and fetchArt to update artPath safely
+// from whichever goroutine currently holds the result.
+func (p *Player) setArtPath(path string) {
+	p.artPathMu.Lock()
+	p.artPath = path
+	p.artPathMu.Unlock()
+}
+
+// NewPlayer returns a new player object. noArt disables cover art
+// download/caching for it (see Refresh).
+func NewPlayer(conn *dbus.Conn, name string, interpolate bool, poll int, noArt bool) (p *Player) {
 	playerName := strings.ReplaceAll(name, INTERFACE+".", "")
 	var pid uint32
 	conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, name).Store(&pid)
@@ -76,11 +104,18 @@ func NewPlayer(conn *dbus.Conn, name string, interpolate bool, poll int) (p *Pla
 		pid:         pid,
 		interpolate: interpolate,
 		poll:        poll,
+		noArt:       noArt,
 	}
 	p.Refresh()
 	return
 }
 
+func (p *Player) Exists() bool {
+	pid := uint32(0)
+	p.conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, p.FullName).Store(&pid)
+	return pid != 0
+}
+
 func (p *Player) String() string {
 	return fmt.Sprintf("Name: %s; Playing: %t; PID: %d", p.FullName, p.Playing, p.pid)
 }
@@ -96,6 +131,11 @@ func (p *Player) Refresh() (err error) {
 		p.Artist = ""
 		p.AlbumArtist = ""
 		p.Album = ""
+		p.Genre = ""
+		p.ArtURL = ""
+		p.setArtPath("")
+		p.TrackNumber = -1
+		p.Length = 0
 		return
 	}
 	strVal := val.String()
@@ -116,6 +156,11 @@ func (p *Player) Refresh() (err error) {
 		p.Artist = ""
 		p.AlbumArtist = ""
 		p.Album = ""
+		p.Genre = ""
+		p.ArtURL = ""
+		p.setArtPath("")
+		p.TrackNumber = -1
+		p.Length = 0
 		return
 	}
 	p.metadata = metadata.Value().(map[string]dbus.Variant)
@@ -147,43 +192,121 @@ func (p *Player) Refresh() (err error) {
 	default:
 		p.Album = ""
 	}
+	switch genre := p.metadata["xesam:genre"].Value().(type) {
+	case []string:
+		p.Genre = strings.Join(genre, ", ")
+	case string:
+		p.Genre = genre
+	default:
+		p.Genre = ""
+	}
+	switch artURL := p.metadata["mpris:artUrl"].Value().(type) {
+	case string:
+		p.ArtURL = artURL
+	default:
+		p.ArtURL = ""
+	}
+	if p.ArtURL == "" {
+		p.setArtPath("")
+		p.lastArtURL = ""
+	} else if !p.noArt && p.ArtURL != p.lastArtURL {
+		p.lastArtURL = p.ArtURL
+		p.setArtPath("")
+		go p.fetchArt(p.ArtURL)
+	}
+	switch trackNumber := p.metadata["xesam:trackNumber"].Value().(type) {
+	case int32:
+		p.TrackNumber = int(trackNumber)
+	default:
+		p.TrackNumber = -1
+	}
+	switch length := p.metadata["mpris:length"].Value().(type) {
+	case int64:
+		p.Length = int(length) / 1000000
+	case uint64:
+		p.Length = int(length) / 1000000
+	default:
+		p.Length = 0
+	}
 	return nil
 }
 
 func µsToString(µs int64) string {
-	seconds := int(µs / 1e6)
+	seconds := int(µs / 1000000)
 	minutes := int(seconds / 60)
 	seconds -= minutes * 60
 	return fmt.Sprintf("%02d:%02d", minutes, seconds)
 }
 
+func (p *Player) GetPosition() bool {
+	pos, err := p.Player.GetProperty(INTERFACE + ".Player.Position")
+	if err != nil {
+		return false
+	}
+	switch position := pos.Value().(type) {
+	case int64:
+		p.Position = position
+	case uint64:
+		p.Position = int64(position)
+	default:
+		p.Position = 0
+		return false
+	}
+	return true
+}
+
+// Returns value instead of writing it.
+func (p *Player) getPosition() (int64, bool) {
+	pos, err := p.Player.GetProperty(INTERFACE + ".Player.Position")
+	if err != nil {
+		return 0, false
+	}
+	switch position := pos.Value().(type) {
+	case int64:
+		return position, true
+	case uint64:
+		return int64(position), true
+	default:
+		return 0, false
+	}
+}
+
 // StringPosition figures out the track position in MM:SS/MM:SS, interpolating the value if necessary.
 func (p *Player) StringPosition() string {
 	// position is in microseconds so we prob need int64 to be safe
 	v := p.metadata["mpris:length"].Value()
 	var l int64
-	if v != nil {
-		l = v.(int64)
-	} else {
+	switch val := v.(type) {
+	case int64:
+		l = val
+	case uint64:
+		l = int64(val)
+	default:
+		return ""
+	}
+	if l == 0 {
 		return ""
 	}
 	length := µsToString(l)
 	if length == "" {
 		return ""
 	}
-	pos, err := p.Player.GetProperty(INTERFACE + ".Player.Position")
-	if err != nil {
+	pos, ok := p.getPosition()
+	if !ok {
 		return ""
 	}
-	position := µsToString(pos.Value().(int64))
+	if pos == 0 {
+		return ""
+	}
+	position := µsToString(pos)
 	if position == "" {
 		return ""
 	}
 	if p.interpolate && position == µsToString(p.Position) {
-		np := p.Position + int64(p.poll*1e6)
+		np := p.Position + int64(p.poll*1000000)
 		position = µsToString(np)
 	}
-	p.Position = pos.Value().(int64)
+	p.Position = pos
 	return position + "/" + length
 }
 
@@ -206,15 +329,23 @@ func (ls PlayerArray) Len() int {
 	return len(ls)
 }
 
+// Less orders by playing state first (playing before paused/stopped), then
+// by Priority, then alphabetically by Name.
 func (ls PlayerArray) Less(i, j int) bool {
 	var states [2]uint8
-	for i, p := range []bool{ls[i].Playing, ls[j].Playing} {
+	for k, p := range []bool{ls[i].Playing, ls[j].Playing} {
 		if p {
-			states[i] = 1
+			states[k] = 1
 		}
 	}
-	// Reverse order
-	return states[0] > states[1]
+	if states[0] != states[1] {
+		// Reverse order
+		return states[0] > states[1]
+	}
+	if pi, pj := priorityIndex(ls[i].Name), priorityIndex(ls[j].Name); pi != pj {
+		return pi < pj
+	}
+	return ls[i].Name < ls[j].Name
 }
 
 func (ls PlayerArray) Swap(i, j int) {
@@ -229,9 +360,14 @@ type Mpris2 struct {
 	interpolate bool
 	poll        int
 	autofocus   bool
+	noArt       bool
+	// playerctld mirrors property changes of other players, so we store its UID here to ignore it.
+	playerctldUID string
 }
 
-func NewMpris2(conn *dbus.Conn, interpolate bool, poll int, autofocus bool) *Mpris2 {
+// NewMpris2 returns a new Mpris2. noArt disables cover art download/caching
+// for every player it creates.
+func NewMpris2(conn *dbus.Conn, interpolate bool, poll int, autofocus bool, noArt bool) *Mpris2 {
 	return &Mpris2{
 		List:        PlayerArray{},
 		Current:     0,
@@ -239,6 +375,7 @@ func NewMpris2(conn *dbus.Conn, interpolate bool, poll int, autofocus bool) *Mpr
 		Messages:    make(chan Message),
 		interpolate: interpolate,
 		poll:        poll,
+		noArt:       noArt,
 	}
 }
 
@@ -254,8 +391,11 @@ func (pl *Mpris2) Listen() {
 			case string:
 				var pid uint32
 				pl.conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixProcessID", 0, name).Store(&pid)
-				// Ignore playerctld again
-				if strings.Contains(name, INTERFACE) && !strings.Contains(name, "playerctld") {
+				// Ignore playerctld
+				if strings.Contains(name, "playerctld") {
+					// Store UID so we know to ignore it later
+					pl.playerctldUID = v.Sender
+				} else if strings.Contains(name, INTERFACE) {
 					if pid == 0 {
 						pl.Remove(name)
 						pl.Messages <- Message{Name: "remove", Value: name}
@@ -265,13 +405,17 @@ func (pl *Mpris2) Listen() {
 					}
 				}
 			}
-		} else if strings.Contains(v.Name, "PropertiesChanged") && strings.Contains(v.Body[0].(string), INTERFACE+".Player") {
+		} else if strings.Contains(v.Name, "PropertiesChanged") && strings.Contains(v.Body[0].(string), INTERFACE+".Player") && v.Sender != pl.playerctldUID {
 			pl.Refresh()
 		}
 	}
 }
 
 func (pl *Mpris2) Remove(fullName string) {
+	if len(pl.List) == 0 {
+		// Nothing tracked, e.g. it was filtered out by Include/Exclude.
+		return
+	}
 	currentName := pl.List[pl.Current].FullName
 	var i int
 	found := false
@@ -310,7 +454,12 @@ func (pl *Mpris2) Reload() error {
 	}
 	for _, name := range buses {
 		// Don't add playerctld, it just duplicates other players
-		if strings.HasPrefix(name, INTERFACE) && !strings.Contains(name, "playerctld") {
+		if strings.Contains(name, "playerctld") {
+			// Store its UID
+			uid := ""
+			pl.conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, name).Store(&uid)
+			pl.playerctldUID = uid
+		} else if strings.HasPrefix(name, INTERFACE) {
 			pl.New(name)
 		}
 	}
@@ -335,8 +484,13 @@ func (pl *Mpris2) String() string {
 	return resp
 }
 
+// New adds the player at name, unless Include/Exclude rule it out.
 func (pl *Mpris2) New(name string) {
-	pl.List = append(pl.List, NewPlayer(pl.conn, name, pl.interpolate, pl.poll))
+	p := NewPlayer(pl.conn, name, pl.interpolate, pl.poll, pl.noArt)
+	if !allowed(p.FullName, p.Name) {
+		return
+	}
+	pl.List = append(pl.List, p)
 	if pl.autofocus {
 		pl.Current = uint(len(pl.List) - 1)
 	}
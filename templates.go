@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	mpris2 "git.hrfee.pw/hrfee/waybar-mpris/mpris2client"
+)
+
+// Default templates, used when --template-file isn't given, or a section in
+// it doesn't override a particular field.
+const (
+	defaultClassTemplate   = `{{if .Playing}}playing{{else}}paused{{end}}`
+	defaultTextTemplate    = `{{if .Playing}}▶ {{else}}⏸ {{end}}{{if .Artist}}{{.Artist}} - {{end}}{{if .Album}}{{.Album}} - {{end}}{{.Title}}{{if .Position}} ({{.Position}}){{end}}`
+	defaultTooltipTemplate = "{{if .ArtPath}}<img src=\"{{.ArtPath}}\" size=\"{{.ArtSize}}\"/>\n{{end}}{{.Title | pango}}\nby {{.Artist | pango}}\n{{if .Album}}from {{.Album | pango}}\n{{end}}({{.Name | pango}})"
+	defaultAltTemplate     = `{{if .Playing}}Playing{{else}}Paused{{end}}`
+)
+
+// templateFuncs are available to every template parsed via parseTemplate.
+var templateFuncs = template.FuncMap{
+	// pango escapes the characters Pango markup treats specially, so
+	// track metadata (which may contain "&", "<" or ">") doesn't break
+	// waybar's tooltip parsing when interpolated into it.
+	"pango": func(s string) string {
+		r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+		return r.Replace(s)
+	},
+}
+
+// templateContext is the value passed to the output templates. It embeds
+// the full player, so templates can reference title, artist, album,
+// albumArtist, playing, name, fullName, artURL, artPath, trackNumber and
+// genre directly, plus a few fields computed here because they need a live
+// DBus round-trip or a flag value rather than just reading metadata.
+type templateContext struct {
+	*mpris2.Player
+	Position   string
+	Percentage int
+	ArtSize    int
+}
+
+func newTemplateContext(p *mpris2.Player) *templateContext {
+	ctx := &templateContext{Player: p, ArtSize: ART_SIZE}
+	pos := p.StringPosition()
+	if SHOW_POS {
+		ctx.Position = pos
+	}
+	if p.Length > 0 {
+		ctx.Percentage = int(p.Position/1e6) * 100 / p.Length
+		if ctx.Percentage > 100 {
+			ctx.Percentage = 100
+		}
+	}
+	return ctx
+}
+
+// fieldTemplates holds the parsed templates for one "[default]" or
+// "[players.<name>]" section of a --template-file.
+type fieldTemplates struct {
+	Class, Text, Tooltip, Alt *template.Template
+}
+
+// templateConfig is the result of parsing a --template-file: a default set
+// of templates, plus per-player overrides keyed by player name.
+type templateConfig struct {
+	Default fieldTemplates
+	Players map[string]fieldTemplates
+}
+
+// rawFields mirrors fieldTemplates, holding un-parsed template source.
+type rawFields struct {
+	Class, Text, Tooltip, Alt string
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// buildFieldTemplates parses raw into a fieldTemplates, falling back to
+// fallback's templates for any field raw leaves unset.
+func buildFieldTemplates(section string, raw rawFields, fallback fieldTemplates) (ft fieldTemplates, err error) {
+	ft = fallback
+	if raw.Class != "" {
+		if ft.Class, err = parseTemplate(section+".class", raw.Class); err != nil {
+			return
+		}
+	}
+	if raw.Text != "" {
+		if ft.Text, err = parseTemplate(section+".text", raw.Text); err != nil {
+			return
+		}
+	}
+	if raw.Tooltip != "" {
+		if ft.Tooltip, err = parseTemplate(section+".tooltip", raw.Tooltip); err != nil {
+			return
+		}
+	}
+	if raw.Alt != "" {
+		if ft.Alt, err = parseTemplate(section+".alt", raw.Alt); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func defaultFieldTemplates() fieldTemplates {
+	ft, err := buildFieldTemplates("default", rawFields{
+		Class:   defaultClassTemplate,
+		Text:    defaultTextTemplate,
+		Tooltip: defaultTooltipTemplate,
+		Alt:     defaultAltTemplate,
+	}, fieldTemplates{})
+	if err != nil {
+		// The built-in defaults are parsed at init time and known-good.
+		panic(err)
+	}
+	return ft
+}
+
+// loadTemplateConfig reads a --template-file. The format is a minimal INI:
+//
+//	[default]
+//	text = "{{.Artist}} - {{.Title}}"
+//
+//	[players.Spotify]
+//	text = "♫ {{.Title}}"
+//
+// Section names under "players." are matched against a player's friendly
+// Name (see mpris2client.NewPlayer). Values may be double-quoted to embed
+// escapes like \n; unquoted values are used verbatim.
+func loadTemplateConfig(path string) (*templateConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := map[string]rawFields{}
+	order := []string{}
+	section := "default"
+	sections[section] = rawFields{}
+	order = append(order, section)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			if _, ok := sections[section]; !ok {
+				sections[section] = rawFields{}
+				order = append(order, section)
+			}
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:idx])
+		val := strings.TrimSpace(line[idx+1:])
+		if len(val) >= 2 && strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") {
+			if unquoted, err := strconv.Unquote(val); err == nil {
+				val = unquoted
+			}
+		}
+		fields := sections[section]
+		switch strings.ToLower(key) {
+		case "class":
+			fields.Class = val
+		case "text":
+			fields.Text = val
+		case "tooltip":
+			fields.Tooltip = val
+		case "alt":
+			fields.Alt = val
+		}
+		sections[section] = fields
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	cfg := &templateConfig{
+		Default: defaultFieldTemplates(),
+		Players: map[string]fieldTemplates{},
+	}
+	for _, name := range order {
+		raw := sections[name]
+		if name == "default" {
+			if cfg.Default, err = buildFieldTemplates(name, raw, cfg.Default); err != nil {
+				return nil, fmt.Errorf("template-file: [%s]: %w", name, err)
+			}
+			continue
+		}
+		playerName := strings.TrimPrefix(name, "players.")
+		if playerName == name {
+			// Not a section we recognise, e.g. a typo'd header.
+			continue
+		}
+		ft, err := buildFieldTemplates(name, raw, cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("template-file: [%s]: %w", name, err)
+		}
+		cfg.Players[playerName] = ft
+	}
+	return cfg, nil
+}
+
+// fieldsFor returns the templates that apply to p, preferring a
+// "[players.<name>]" override over the default set.
+func (c *templateConfig) fieldsFor(p *mpris2.Player) fieldTemplates {
+	if c == nil {
+		return defaultFieldTemplates()
+	}
+	if ft, ok := c.Players[p.Name]; ok {
+		return ft
+	}
+	if ft, ok := c.Players[p.FullName]; ok {
+		return ft
+	}
+	return c.Default
+}
+
+func execTemplate(t *template.Template, ctx *templateContext) string {
+	if t == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ctx); err != nil {
+		log.Println("Couldn't execute template:", err)
+		return ""
+	}
+	return buf.String()
+}
+
+// renderJSON runs p through the templates in TEMPLATES (falling back to the
+// built-in defaults) and marshals the result into the JSON object waybar
+// expects.
+func renderJSON(p *mpris2.Player) string {
+	ctx := newTemplateContext(p)
+	ft := TEMPLATES.fieldsFor(p)
+	out := map[string]interface{}{
+		"class":   execTemplate(ft.Class, ctx),
+		"text":    execTemplate(ft.Text, ctx),
+		"tooltip": execTemplate(ft.Tooltip, ctx),
+	}
+	if alt := execTemplate(ft.Alt, ctx); alt != "" {
+		out["alt"] = alt
+	}
+	if ctx.Percentage > 0 {
+		out["percentage"] = ctx.Percentage
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}